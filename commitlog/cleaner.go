@@ -0,0 +1,187 @@
+package commitlog
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cleaner runs Log.Clean on a timer, enforcing whatever CleanupPolicy the
+// log was configured with.
+type cleaner struct {
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+func (l *Log) startCleaner() {
+	c := &cleaner{
+		ticker: time.NewTicker(l.CleanerInterval),
+		done:   make(chan struct{}),
+	}
+	l.cleaner = c
+	go l.cleanLoop(c)
+}
+
+func (l *Log) cleanLoop(c *cleaner) {
+	for {
+		select {
+		case <-c.ticker.C:
+			// A failed pass is retried on the next tick rather than
+			// aborting the cleaner; there's no one to report the error to
+			// from a background goroutine.
+			_ = l.Clean()
+		case <-c.done:
+			c.ticker.Stop()
+			return
+		}
+	}
+}
+
+func (c *cleaner) stop() {
+	close(c.done)
+}
+
+// Clean runs one pass of the log's configured CleanupPolicy over its
+// non-active segments. It is safe to call concurrently with Append and
+// with the background cleaner; Clean serializes itself internally. The
+// scan-and-rewrite work runs without holding the log's main mutex, so
+// Append is never blocked for the duration of a pass — only for the
+// brief moment it takes to swap in the result.
+func (l *Log) Clean() error {
+	l.cleanMu.Lock()
+	defer l.cleanMu.Unlock()
+
+	switch l.CleanupPolicy {
+	case CleanupPolicyCompact:
+		return l.compact()
+	case CleanupPolicyDelete, "":
+		return l.deleteExpired()
+	default:
+		return errors.Errorf("commitlog: unknown cleanup policy %q", l.CleanupPolicy)
+	}
+}
+
+// deleteExpired removes whole segments that have aged out under
+// RetentionMs or that push the log's total size past MaxLogBytes. The
+// active segment is never removed.
+func (l *Log) deleteExpired() error {
+	l.mu.Lock()
+	active := l.active()
+	segments := append([]*segment(nil), l.segments...)
+	l.mu.Unlock()
+
+	var size int64
+	for _, s := range segments {
+		size += s.Position
+	}
+
+	var removed []*segment
+	for _, s := range segments {
+		if s == active {
+			continue
+		}
+
+		expiredBySize := l.MaxLogBytes > 0 && size > l.MaxLogBytes
+		expiredByAge := l.RetentionMs > 0 && time.Now().UnixMilli()-s.MaxTimestamp > l.RetentionMs
+		if !expiredBySize && !expiredByAge {
+			continue
+		}
+
+		size -= s.Position
+		if err := s.Remove(); err != nil {
+			return errors.Wrapf(err, "commitlog: remove segment %d", s.BaseOffset)
+		}
+		removed = append(removed, s)
+	}
+
+	l.mu.Lock()
+	l.removeSegments(removed)
+	l.mu.Unlock()
+	return nil
+}
+
+// compact rewrites every non-active segment so that, for each key, only
+// the record at that key's latest offset survives; a tombstone (nil
+// value) that is itself the latest record for its key deletes the key
+// entirely. The scan-and-rewrite itself runs outside l.mu; only the
+// final file-handle swap in compactSegment takes the segment's own
+// Mutex, mirroring roll()'s minimal critical section.
+func (l *Log) compact() error {
+	l.mu.Lock()
+	active := l.active()
+	segments := append([]*segment(nil), l.segments...)
+	l.mu.Unlock()
+
+	for _, s := range segments {
+		if s == active {
+			continue
+		}
+		if err := l.compactSegment(s); err != nil {
+			return errors.Wrapf(err, "commitlog: compact segment %d", s.BaseOffset)
+		}
+	}
+	return nil
+}
+
+func (l *Log) compactSegment(s *segment) error {
+	latestOffset := make(map[string]int64)
+	if err := s.scan(func(offset int64, msg Message) error {
+		if key := msg.Key(); key != nil {
+			latestOffset[string(key)] = offset
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	cleanedPath := s.path + cleanedSuffix
+	cleaned, err := os.OpenFile(cleanedPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	writeErr := s.scan(func(offset int64, msg Message) error {
+		key := msg.Key()
+		switch {
+		case key == nil:
+			// Keyless records (e.g. control records) always survive.
+		case latestOffset[string(key)] != offset:
+			return nil // superseded by a later write for this key
+		case msg.Value() == nil:
+			return nil // tombstone is the latest write: drop the key
+		}
+		return writeMessageAsBatch(cleaned, offset, msg)
+	})
+	closeErr := cleaned.Close()
+	if writeErr != nil {
+		os.Remove(cleanedPath)
+		return writeErr
+	}
+	if closeErr != nil {
+		os.Remove(cleanedPath)
+		return closeErr
+	}
+
+	logPath := s.path + logSuffix
+
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.log.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(cleanedPath, logPath); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.log = f
+	s.writer = f
+	s.reader = f
+
+	return s.rebuildIndex()
+}
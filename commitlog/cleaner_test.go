@@ -0,0 +1,142 @@
+package commitlog
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// roll swaps in a fresh active segment on l, returning the segment that
+// was previously active so tests can clean it while it's no longer live.
+func roll(t *testing.T, l *Log) *segment {
+	t.Helper()
+
+	old := l.active()
+	next, err := newSegment(l.Path, old.NextOffset, l.MaxSegmentBytes)
+	if err != nil {
+		t.Fatalf("newSegment() error = %v", err)
+	}
+	l.segments = append(l.segments, next)
+	l.activeSegment.Store(next)
+	return old
+}
+
+func TestLogCompactionDropsTombstonedKey(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, CleanupPolicy: CleanupPolicyCompact})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	old := l.active()
+	writeMsgs(t, old,
+		createTestMessage(1, 0, 1, []byte("k1"), []byte("v1")),
+		createTestMessage(1, 0, 2, []byte("k1"), nil), // tombstone: latest write for k1
+		createTestMessage(1, 0, 3, []byte("k2"), []byte("v2")),
+	)
+	roll(t, l)
+
+	if err := l.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	var keys []string
+	if err := old.scan(func(offset int64, msg Message) error {
+		keys = append(keys, string(msg.Key()))
+		return nil
+	}); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "k2" {
+		t.Errorf("compact() survivors = %v, want only [k2]", keys)
+	}
+}
+
+func TestLogCompactionKeepsLatestPerKey(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, CleanupPolicy: CleanupPolicyCompact})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	old := l.active()
+	writeMsgs(t, old,
+		createTestMessage(1, 0, 1, []byte("k1"), []byte("v1")),
+		createTestMessage(1, 0, 2, []byte("k1"), []byte("v2")),
+	)
+	roll(t, l)
+
+	if err := l.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	var values []string
+	if err := old.scan(func(offset int64, msg Message) error {
+		values = append(values, string(msg.Value()))
+		return nil
+	}); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+
+	if len(values) != 1 || values[0] != "v2" {
+		t.Errorf("compact() survivors = %v, want only [v2]", values)
+	}
+}
+
+func TestLogCleanupPolicyDeleteBySize(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, MaxLogBytes: 1, CleanupPolicy: CleanupPolicyDelete})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	old := l.active()
+	writeMsgs(t, old, createTestMessage(1, 0, 1, []byte("k"), []byte("v")))
+	roll(t, l)
+
+	if err := l.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	if len(l.segments) != 1 || l.segments[0] != l.active() {
+		t.Errorf("deleteExpired() left %d segments, want only the active one", len(l.segments))
+	}
+	if _, err := os.Stat(old.path + old.suffix); !os.IsNotExist(err) {
+		t.Errorf("expected old segment's log file to be removed, stat err = %v", err)
+	}
+}
+
+func TestLogCleanupPolicyDeleteByAge(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, RetentionMs: 1, CleanupPolicy: CleanupPolicyDelete})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	old := l.active()
+	stale := time.Now().Add(-time.Hour).UnixMilli()
+	writeMsgs(t, old, createTestMessage(1, 0, stale, []byte("k"), []byte("v")))
+	roll(t, l)
+
+	if err := l.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+
+	if len(l.segments) != 1 {
+		t.Errorf("deleteExpired() by age left %d segments, want 1", len(l.segments))
+	}
+}
+
+func writeMsgs(t *testing.T, s *segment, msgs ...Message) {
+	t.Helper()
+	for _, m := range msgs {
+		if _, err := s.Write(m); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+}
@@ -2,6 +2,10 @@ package commitlog
 
 import (
 	"encoding/binary"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -12,6 +16,16 @@ var (
 )
 
 type CleanupPolicy string
+
+const (
+	// CleanupPolicyDelete drops whole segments once MaxLogBytes is
+	// exceeded or RetentionMs has elapsed since a segment's newest
+	// message.
+	CleanupPolicyDelete CleanupPolicy = "delete"
+	// CleanupPolicyCompact keeps only the latest message per key.
+	CleanupPolicyCompact CleanupPolicy = "compact"
+)
+
 type Options struct {
 	Path string
 	// MaxSegmentBytes is the max number of bytes a segment can contain, once the limit is hit a
@@ -19,4 +33,204 @@ type Options struct {
 	MaxSegmentBytes int64
 	MaxLogBytes     int64
 	CleanupPolicy   CleanupPolicy
+	// RetentionMs is how long a segment may live, measured from its
+	// newest message timestamp, before CleanupPolicyDelete removes it.
+	// Zero disables time-based retention.
+	RetentionMs int64
+	// CleanerInterval controls how often the background cleaner scans
+	// the log. Zero disables the background cleaner; Clean can still be
+	// called manually.
+	CleanerInterval time.Duration
+	// MaxSegmentAge rolls the active segment once its oldest message is
+	// older than this, even if MaxSegmentBytes hasn't been reached. Zero
+	// disables age-based rolling.
+	MaxSegmentAge time.Duration
+	// FlushInterval fsyncs the active segment on a timer, independent of
+	// how many messages have been appended. Zero disables the background
+	// flusher.
+	FlushInterval time.Duration
+	// FlushMessages forces an fsync after every FlushMessages appends.
+	// Zero disables count-based flushing.
+	FlushMessages int
+	// SyncOnAppend forces an fsync after every single Append.
+	SyncOnAppend bool
+}
+
+// Log is a single append-only partition: an ordered sequence of segments
+// rooted at Options.Path.
+type Log struct {
+	Options
+
+	mu       sync.Mutex
+	segments []*segment
+	// activeSegment holds the current *segment behind an atomic.Value so
+	// Subscribe followers (see rolledPast) can observe a roll without
+	// contending on mu; only roll itself writes it, guarded by mu plus a
+	// brief hold of the outgoing segment's own Mutex.
+	activeSegment atomic.Value
+	// appendsSinceSync counts appends since the active segment was last
+	// fsynced, driving FlushMessages. Only touched from Append, which
+	// holds mu.
+	appendsSinceSync int
+	// cond is broadcast on every successful Append and on every segment
+	// roll, waking Subscribe followers blocked waiting for new data.
+	cond *sync.Cond
+
+	// cleanMu serializes Clean passes (manual calls racing the background
+	// cleaner) without forcing them to hold mu — and therefore block
+	// Append — for the whole scan-and-rewrite; see cleaner.go.
+	cleanMu sync.Mutex
+	cleaner *cleaner
+	flusher *flusher
+}
+
+// active returns the log's current active segment.
+func (l *Log) active() *segment {
+	return l.activeSegment.Load().(*segment)
+}
+
+// NewLog opens the log rooted at opts.Path, creating it if necessary, and
+// starts its background cleaner if opts.CleanerInterval is set.
+func NewLog(opts Options) (*Log, error) {
+	if opts.Path == "" {
+		return nil, errors.New("commitlog: Options.Path is required")
+	}
+	if err := os.MkdirAll(opts.Path, 0755); err != nil {
+		return nil, err
+	}
+
+	seg, err := newSegment(opts.Path, 0, opts.MaxSegmentBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		Options:  opts,
+		segments: []*segment{seg},
+	}
+	l.activeSegment.Store(seg)
+	l.cond = sync.NewCond(&l.mu)
+	if opts.CleanerInterval > 0 {
+		l.startCleaner()
+	}
+	if opts.FlushInterval > 0 {
+		l.startFlusher()
+	}
+	return l, nil
+}
+
+// Append writes msg to the active segment and returns its offset. A new
+// segment is rolled in before Append returns if the write pushed the
+// active segment past MaxSegmentBytes, or if the active segment's oldest
+// message is now older than MaxSegmentAge. Subscribe followers blocked on
+// new data are woken regardless of whether a roll happened.
+func (l *Log) Append(msg Message) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	active := l.active()
+	offset, err := active.Write(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	rollNeeded := l.MaxSegmentBytes > 0 && active.Position >= l.MaxSegmentBytes
+	if !rollNeeded && l.MaxSegmentAge > 0 && active.FirstTimestamp > 0 {
+		age := time.Since(time.UnixMilli(active.FirstTimestamp))
+		rollNeeded = age >= l.MaxSegmentAge
+	}
+	if rollNeeded {
+		if err := l.roll(); err != nil {
+			return offset, err
+		}
+	}
+
+	l.appendsSinceSync++
+	if l.SyncOnAppend || (l.FlushMessages > 0 && l.appendsSinceSync >= l.FlushMessages) {
+		l.appendsSinceSync = 0
+		if err := active.log.Sync(); err != nil {
+			return offset, err
+		}
+	}
+
+	l.cond.Broadcast()
+	return offset, nil
+}
+
+// roll starts a new active segment at the current high-water mark and
+// swaps it in. Callers must hold l.mu, which still serializes concurrent
+// rolls and appends to l.segments; the outgoing segment's own Mutex is
+// held only around the atomic swap itself, and Subscribe followers read
+// the new active segment through that atomic value without ever needing
+// l.mu.
+func (l *Log) roll() error {
+	old := l.active()
+	next, err := newSegment(l.Path, old.NextOffset, l.MaxSegmentBytes)
+	if err != nil {
+		return err
+	}
+
+	old.Lock()
+	l.segments = append(l.segments, next)
+	l.activeSegment.Store(next)
+	old.Unlock()
+	return nil
+}
+
+// Sync fsyncs the active segment's log file, flushing appends the OS has
+// buffered but not yet written to disk.
+func (l *Log) Sync() error {
+	return l.active().log.Sync()
+}
+
+// removeSegments drops every segment in removed from l.segments. Callers
+// must hold l.mu. It matches by pointer identity so it's safe even if a
+// roll added new segments since removed was computed.
+func (l *Log) removeSegments(removed []*segment) {
+	if len(removed) == 0 {
+		return
+	}
+	gone := make(map[*segment]struct{}, len(removed))
+	for _, s := range removed {
+		gone[s] = struct{}{}
+	}
+
+	kept := l.segments[:0:0]
+	for _, s := range l.segments {
+		if _, remove := gone[s]; !remove {
+			kept = append(kept, s)
+		}
+	}
+	l.segments = kept
+}
+
+// Segments returns a snapshot of the log's current segments, oldest first.
+func (l *Log) Segments() []*segment {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]*segment, len(l.segments))
+	copy(out, l.segments)
+	return out
+}
+
+// Close stops the background cleaner, if any, and closes every segment.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cleaner != nil {
+		l.cleaner.stop()
+	}
+	if l.flusher != nil {
+		l.flusher.stop()
+	}
+	for _, s := range l.segments {
+		if err := s.log.Close(); err != nil {
+			return err
+		}
+		if err := s.Index.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
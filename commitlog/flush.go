@@ -0,0 +1,38 @@
+package commitlog
+
+import "time"
+
+// flusher runs Log.Sync on a timer, independent of FlushMessages/
+// SyncOnAppend, so writes are bounded by wall-clock time even during a
+// lull in appends.
+type flusher struct {
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+func (l *Log) startFlusher() {
+	f := &flusher{
+		ticker: time.NewTicker(l.FlushInterval),
+		done:   make(chan struct{}),
+	}
+	l.flusher = f
+	go l.flushLoop(f)
+}
+
+func (l *Log) flushLoop(f *flusher) {
+	for {
+		select {
+		case <-f.ticker.C:
+			// Best-effort, same as the cleaner: retried next tick rather
+			// than aborting the background goroutine.
+			_ = l.Sync()
+		case <-f.done:
+			f.ticker.Stop()
+			return
+		}
+	}
+}
+
+func (f *flusher) stop() {
+	close(f.done)
+}
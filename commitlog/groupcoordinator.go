@@ -0,0 +1,280 @@
+package commitlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// consumerOffsetsDir names the directory, under the coordinator's root,
+// holding the internal compacted log that backs it — analogous to
+// Kafka's __consumer_offsets topic.
+const consumerOffsetsDir = "__consumer_offsets"
+
+// ErrGroupNotFound is returned by Fetch when nothing has been committed
+// for the requested group/topic/partition.
+var ErrGroupNotFound = errors.New("commitlog: group offset not found")
+
+// groupOffset is the in-memory view of the latest commit for a single
+// group/topic/partition. LogOffset is the backing-log offset the commit
+// was appended at, so a racing pair of concurrent Commit calls can never
+// let the map regress behind an offset it has already applied.
+type groupOffset struct {
+	Offset    int64
+	Metadata  []byte
+	CommitTs  int64
+	LogOffset int64
+}
+
+// offsetSnapshot is the on-disk checkpoint format: the full offsets map,
+// plus Through, one past the highest backing-log offset it reflects, so
+// recover only has to replay commits appended at or after Through.
+type offsetSnapshot struct {
+	Through int64                  `json:"through"`
+	Offsets map[string]groupOffset `json:"offsets"`
+}
+
+// GroupCoordinator tracks consumer-group offsets the way Kafka's
+// __consumer_offsets topic does: every commit is appended as a keyed
+// message (key = group|topic|partition) to a dedicated compacted Log, so
+// replaying the log from its start always reconstructs the latest offset
+// per group/topic/partition. A periodic snapshot bounds how much of the
+// log a restart has to replay.
+type GroupCoordinator struct {
+	log *Log
+
+	mu               sync.RWMutex
+	offsets          map[string]groupOffset
+	recoveredThrough int64 // log offsets below this are already reflected in the loaded snapshot
+	logPosition      int64 // one past the highest log offset observed so far, from either recovery or Commit
+
+	snapshotPath string
+	done         chan struct{}
+}
+
+// NewGroupCoordinator opens (creating if necessary) the compacted
+// offsets log rooted at dir, recovers its in-memory state from the
+// latest snapshot plus any commits appended since, and starts a
+// background goroutine that checkpoints a fresh snapshot every
+// checkpointInterval. A zero checkpointInterval disables the background
+// checkpointer; Close still takes a final snapshot.
+func NewGroupCoordinator(dir string, checkpointInterval time.Duration) (*GroupCoordinator, error) {
+	l, err := NewLog(Options{
+		Path:            filepath.Join(dir, consumerOffsetsDir),
+		MaxSegmentBytes: 64 << 20,
+		CleanupPolicy:   CleanupPolicyCompact,
+		CleanerInterval: time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gc := &GroupCoordinator{
+		log:          l,
+		offsets:      make(map[string]groupOffset),
+		snapshotPath: filepath.Join(dir, consumerOffsetsDir+".snapshot"),
+		done:         make(chan struct{}),
+	}
+	if err := gc.recover(); err != nil {
+		return nil, err
+	}
+	if checkpointInterval > 0 {
+		go gc.checkpointLoop(checkpointInterval)
+	}
+	return gc, nil
+}
+
+// Commit persists offset and metadata for group/topic/partition and
+// updates the in-memory view immediately.
+func (gc *GroupCoordinator) Commit(group, topic string, partition int32, offset int64, metadata []byte) error {
+	now := time.Now().UnixMilli()
+	key := groupKey(group, topic, partition)
+	value := encodeGroupValue(offset, metadata, now)
+
+	logOffset, err := gc.log.Append(messageFromRecord(now, key, value))
+	if err != nil {
+		return err
+	}
+
+	gc.mu.Lock()
+	// Two concurrent Commit calls for the same key can have their map
+	// writes land out of append order once each has released Log's own
+	// mutex; only apply this write if it isn't already stale relative to
+	// what's in the map.
+	if existing, ok := gc.offsets[string(key)]; !ok || logOffset > existing.LogOffset {
+		gc.offsets[string(key)] = groupOffset{Offset: offset, Metadata: metadata, CommitTs: now, LogOffset: logOffset}
+	}
+	if logOffset+1 > gc.logPosition {
+		gc.logPosition = logOffset + 1
+	}
+	gc.mu.Unlock()
+	return nil
+}
+
+// Fetch returns the last committed offset and metadata for
+// group/topic/partition, or ErrGroupNotFound if nothing has been
+// committed yet.
+func (gc *GroupCoordinator) Fetch(group, topic string, partition int32) (int64, []byte, error) {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	o, ok := gc.offsets[string(groupKey(group, topic, partition))]
+	if !ok {
+		return 0, nil, ErrGroupNotFound
+	}
+	return o.Offset, o.Metadata, nil
+}
+
+// ListGroups returns the distinct group names with at least one
+// committed offset, sorted for stable output.
+func (gc *GroupCoordinator) ListGroups() []string {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for key := range gc.offsets {
+		if i := strings.IndexByte(key, '|'); i >= 0 {
+			seen[key[:i]] = struct{}{}
+		}
+	}
+	groups := make([]string, 0, len(seen))
+	for g := range seen {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Close stops the background checkpointer, takes a final snapshot, and
+// closes the backing log.
+func (gc *GroupCoordinator) Close() error {
+	close(gc.done)
+	if err := gc.checkpoint(); err != nil {
+		return err
+	}
+	return gc.log.Close()
+}
+
+// recover loads the latest snapshot, if any, and then replays every
+// commit in the backing log at or after the snapshot's Through offset.
+func (gc *GroupCoordinator) recover() error {
+	if err := gc.loadSnapshot(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, s := range gc.log.Segments() {
+		if err := s.scan(func(offset int64, msg Message) error {
+			if offset < gc.recoveredThrough {
+				return nil
+			}
+			gc.applyCommit(offset, msg)
+			if offset+1 > gc.logPosition {
+				gc.logPosition = offset + 1
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gc *GroupCoordinator) applyCommit(logOffset int64, msg Message) {
+	key := string(msg.Key())
+	value := msg.Value()
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if value == nil {
+		delete(gc.offsets, key)
+		return
+	}
+	offset, metadata, commitTs := decodeGroupValue(value)
+	gc.offsets[key] = groupOffset{Offset: offset, Metadata: metadata, CommitTs: commitTs, LogOffset: logOffset}
+}
+
+func (gc *GroupCoordinator) checkpointLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// Best-effort; a failed checkpoint is retried next tick and
+			// recover() falls back to a full replay if none ever lands.
+			_ = gc.checkpoint()
+		case <-gc.done:
+			return
+		}
+	}
+}
+
+// checkpoint atomically writes the coordinator's current state to its
+// snapshot file.
+func (gc *GroupCoordinator) checkpoint() error {
+	gc.mu.RLock()
+	snap := offsetSnapshot{Through: gc.logPosition, Offsets: make(map[string]groupOffset, len(gc.offsets))}
+	for k, v := range gc.offsets {
+		snap.Offsets[k] = v
+	}
+	gc.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	tmp := gc.snapshotPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, gc.snapshotPath)
+}
+
+func (gc *GroupCoordinator) loadSnapshot() error {
+	data, err := os.ReadFile(gc.snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	var snap offsetSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	gc.mu.Lock()
+	gc.offsets = snap.Offsets
+	gc.recoveredThrough = snap.Through
+	gc.logPosition = snap.Through
+	gc.mu.Unlock()
+	return nil
+}
+
+func groupKey(group, topic string, partition int32) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d", group, topic, partition))
+}
+
+// encodeGroupValue lays out a commit record's value as offset(8) +
+// commitTs(8) + metadata.
+func encodeGroupValue(offset int64, metadata []byte, commitTs int64) []byte {
+	buf := make([]byte, 16+len(metadata))
+	Encoding.PutUint64(buf[0:8], uint64(offset))
+	Encoding.PutUint64(buf[8:16], uint64(commitTs))
+	copy(buf[16:], metadata)
+	return buf
+}
+
+func decodeGroupValue(buf []byte) (offset int64, metadata []byte, commitTs int64) {
+	offset = int64(Encoding.Uint64(buf[0:8]))
+	commitTs = int64(Encoding.Uint64(buf[8:16]))
+	if len(buf) > 16 {
+		metadata = buf[16:]
+	}
+	return offset, metadata, commitTs
+}
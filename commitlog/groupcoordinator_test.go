@@ -0,0 +1,156 @@
+package commitlog
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGroupCoordinatorCommitAndFetch(t *testing.T) {
+	dir := t.TempDir()
+	gc, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() error = %v", err)
+	}
+	defer gc.Close()
+
+	if err := gc.Commit("g1", "topic-a", 0, 42, []byte("meta")); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	offset, metadata, err := gc.Fetch("g1", "topic-a", 0)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if offset != 42 || string(metadata) != "meta" {
+		t.Errorf("Fetch() = (%d, %q), want (42, \"meta\")", offset, metadata)
+	}
+
+	if _, _, err := gc.Fetch("g1", "topic-a", 1); err != ErrGroupNotFound {
+		t.Errorf("Fetch() on uncommitted partition error = %v, want ErrGroupNotFound", err)
+	}
+}
+
+func TestGroupCoordinatorListGroups(t *testing.T) {
+	dir := t.TempDir()
+	gc, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() error = %v", err)
+	}
+	defer gc.Close()
+
+	if err := gc.Commit("g1", "topic-a", 0, 1, nil); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := gc.Commit("g2", "topic-a", 0, 1, nil); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := gc.Commit("g1", "topic-b", 0, 1, nil); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	groups := gc.ListGroups()
+	if len(groups) != 2 || groups[0] != "g1" || groups[1] != "g2" {
+		t.Errorf("ListGroups() = %v, want [g1 g2]", groups)
+	}
+}
+
+func TestGroupCoordinatorRecoversFromLogReplay(t *testing.T) {
+	dir := t.TempDir()
+	gc, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() error = %v", err)
+	}
+
+	if err := gc.Commit("g1", "topic-a", 0, 7, []byte("v1")); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := gc.Commit("g1", "topic-a", 0, 9, []byte("v2")); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := gc.log.Close(); err != nil {
+		t.Fatalf("log.Close() error = %v", err)
+	}
+
+	reopened, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() (reopen) error = %v", err)
+	}
+	defer reopened.Close()
+
+	offset, metadata, err := reopened.Fetch("g1", "topic-a", 0)
+	if err != nil {
+		t.Fatalf("Fetch() after recovery error = %v", err)
+	}
+	if offset != 9 || string(metadata) != "v2" {
+		t.Errorf("Fetch() after recovery = (%d, %q), want (9, \"v2\")", offset, metadata)
+	}
+}
+
+func TestGroupCoordinatorConcurrentCommitsNeverRegress(t *testing.T) {
+	dir := t.TempDir()
+	gc, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() error = %v", err)
+	}
+	defer gc.Close()
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			if err := gc.Commit("g1", "topic-a", 0, int64(i), nil); err != nil {
+				t.Errorf("Commit() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Whichever commit's log append landed last must be the one reflected
+	// in the map, regardless of which goroutine won the race to take
+	// gc.mu afterward.
+	gc.mu.RLock()
+	got := gc.offsets[string(groupKey("g1", "topic-a", 0))]
+	wantLogOffset := gc.logPosition - 1
+	gc.mu.RUnlock()
+
+	if got.LogOffset != wantLogOffset {
+		t.Errorf("offsets map reflects LogOffset %d, want %d (the last append)", got.LogOffset, wantLogOffset)
+	}
+}
+
+func TestGroupCoordinatorCheckpointRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	gc, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() error = %v", err)
+	}
+
+	if err := gc.Commit("g1", "topic-a", 0, 5, nil); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := gc.checkpoint(); err != nil {
+		t.Fatalf("checkpoint() error = %v", err)
+	}
+	if err := gc.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := NewGroupCoordinator(dir, 0)
+	if err != nil {
+		t.Fatalf("NewGroupCoordinator() (reopen) error = %v", err)
+	}
+	defer reopened.Close()
+
+	if reopened.recoveredThrough == 0 {
+		t.Error("expected recoveredThrough to be restored from the snapshot, got 0")
+	}
+	offset, _, err := reopened.Fetch("g1", "topic-a", 0)
+	if err != nil {
+		t.Fatalf("Fetch() after snapshot reopen error = %v", err)
+	}
+	if offset != 5 {
+		t.Errorf("Fetch() after snapshot reopen = %d, want 5", offset)
+	}
+}
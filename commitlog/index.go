@@ -0,0 +1,92 @@
+package commitlog
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Entry maps a log offset to the byte position of its record within the
+// owning segment's log file.
+type Entry struct {
+	Offset   int64
+	Position int64
+}
+
+const entryWidth = 16 // 8 bytes offset + 8 bytes position
+
+// Index is an append-only sequence of fixed-width Entry records backing a
+// single segment, so seeking to an offset doesn't require a linear scan
+// of the log file itself.
+type Index struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newIndex(path string) (*Index, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Index{file: f}, nil
+}
+
+// WriteEntry appends e to the index.
+func (idx *Index) WriteEntry(e Entry) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	buf := make([]byte, entryWidth)
+	Encoding.PutUint64(buf[0:8], uint64(e.Offset))
+	Encoding.PutUint64(buf[8:16], uint64(e.Position))
+	_, err := idx.file.Write(buf)
+	return err
+}
+
+// Entries returns every entry currently in the index, in append order.
+func (idx *Index) Entries() ([]Entry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, err := idx.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	buf := make([]byte, entryWidth)
+	for {
+		if _, err := io.ReadFull(idx.file, buf); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Offset:   int64(Encoding.Uint64(buf[0:8])),
+			Position: int64(Encoding.Uint64(buf[8:16])),
+		})
+	}
+	return entries, nil
+}
+
+// Truncate discards all entries so the index can be rebuilt from scratch.
+func (idx *Index) Truncate() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := idx.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// Name returns the path of the backing index file.
+func (idx *Index) Name() string {
+	return idx.file.Name()
+}
+
+func (idx *Index) Close() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.file.Close()
+}
@@ -0,0 +1,116 @@
+package commitlog
+
+// Message is a single log record, laid out on the wire/on disk as:
+//
+//	crc(4) magicByte(1) attributes(1) [timestamp(8)] keyLen(4) key? valueLen(4) value?
+//
+// The timestamp field is only present when MagicByte() > 0. A key or value
+// length of -1 (0xFFFFFFFF) denotes a nil key/value rather than an empty
+// one; a nil value is used as a tombstone under CleanupPolicyCompact.
+type Message []byte
+
+const (
+	crcOffset        = 0
+	magicByteOffset  = 4
+	attributesOffset = 5
+	v0TimestampSize  = 0
+	v1TimestampSize  = 8
+)
+
+// NewMessage wraps an already-encoded record. It does not validate or
+// recompute the CRC; callers that build messages from scratch are
+// responsible for laying out the fields above correctly.
+func NewMessage(value []byte) Message {
+	return Message(value)
+}
+
+// Crc returns the record's stored CRC32 checksum.
+func (m Message) Crc() int32 {
+	return int32(Encoding.Uint32(m[crcOffset:magicByteOffset]))
+}
+
+// MagicByte returns the record's format version.
+func (m Message) MagicByte() int8 {
+	return int8(m[magicByteOffset])
+}
+
+// Attributes returns the record's attribute bitmask (e.g. compression codec).
+func (m Message) Attributes() int8 {
+	return int8(m[attributesOffset])
+}
+
+// Timestamp returns the record's timestamp in milliseconds since the
+// epoch. It panics on a v0 message, which carries no timestamp.
+func (m Message) Timestamp() int64 {
+	if m.MagicByte() == 0 {
+		panic("commitlog: v0 message has no timestamp")
+	}
+	start := attributesOffset + 1
+	return int64(Encoding.Uint64(m[start : start+8]))
+}
+
+func (m Message) timestampSize() int32 {
+	if m.MagicByte() == 0 {
+		return v0TimestampSize
+	}
+	return v1TimestampSize
+}
+
+// keyOffsets returns the start and end byte offsets of the key length
+// prefix plus key bytes, and the key's size (-1 if the key is nil).
+func (m Message) keyOffsets() (start, end, size int32) {
+	start = int32(attributesOffset+1) + m.timestampSize()
+	size = int32(Encoding.Uint32(m[start : start+4]))
+	keySize := size
+	if keySize < 0 {
+		keySize = 0
+	}
+	end = start + 4 + keySize
+	return start, end, size
+}
+
+// valueOffsets returns the start and end byte offsets of the value length
+// prefix plus value bytes, and the value's size (-1 if the value is nil).
+func (m Message) valueOffsets() (start, end, size int32) {
+	_, keyEnd, _ := m.keyOffsets()
+	start = keyEnd
+	size = int32(Encoding.Uint32(m[start : start+4]))
+	valueSize := size
+	if valueSize < 0 {
+		valueSize = 0
+	}
+	end = start + 4 + valueSize
+	return start, end, size
+}
+
+// Key returns the record's key, or nil if it has none.
+func (m Message) Key() []byte {
+	start, end, size := m.keyOffsets()
+	if size < 0 {
+		return nil
+	}
+	return m[start+4 : end]
+}
+
+// Value returns the record's value, or nil if it has none (a tombstone
+// under CleanupPolicyCompact).
+func (m Message) Value() []byte {
+	start, end, size := m.valueOffsets()
+	if size < 0 {
+		return nil
+	}
+	return m[start+4 : end]
+}
+
+// Size returns the total encoded length of the message in bytes.
+func (m Message) Size() int32 {
+	_, _, keySize := m.keyOffsets()
+	_, _, valueSize := m.valueOffsets()
+	if keySize < 0 {
+		keySize = 0
+	}
+	if valueSize < 0 {
+		valueSize = 0
+	}
+	return int32(attributesOffset+1) + m.timestampSize() + 4 + keySize + 4 + valueSize
+}
@@ -213,6 +213,17 @@ func TestMessageValue(t *testing.T) {
 	}
 }
 
+func TestMessageValueWithNilKey(t *testing.T) {
+	// Regression test: a nil key's -1 length sentinel must not shift the
+	// value length prefix, or Value() silently returns the wrong bytes.
+	value := []byte("hello")
+	msg := createTestMessage(1, 0, 0, nil, value)
+
+	if !bytes.Equal(msg.Value(), value) {
+		t.Errorf("Value() with nil key failed: expected %v, got %v", value, msg.Value())
+	}
+}
+
 func TestMessageSize(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -293,8 +304,12 @@ func TestMessageKeyOffsets(t *testing.T) {
 				t.Errorf("keyOffsets() size failed: expected %d, got %d", expectedSize, size)
 			}
 
-			// Verify end position
-			expectedEnd := start + 4 + size
+			// Verify end position (a nil key clamps to 0 bytes, not -1)
+			clampedSize := size
+			if clampedSize < 0 {
+				clampedSize = 0
+			}
+			expectedEnd := start + 4 + clampedSize
 			if end != expectedEnd {
 				t.Errorf("keyOffsets() end failed: expected %d, got %d", expectedEnd, end)
 			}
@@ -335,8 +350,12 @@ func TestMessageValueOffsets(t *testing.T) {
 				t.Errorf("valueOffsets() size failed: expected %d, got %d", expectedSize, size)
 			}
 
-			// Verify end position
-			expectedEnd := start + 4 + size
+			// Verify end position (a nil value clamps to 0 bytes, not -1)
+			clampedSize := size
+			if clampedSize < 0 {
+				clampedSize = 0
+			}
+			expectedEnd := start + 4 + clampedSize
 			if end != expectedEnd {
 				t.Errorf("valueOffsets() end failed: expected %d, got %d", expectedEnd, end)
 			}
@@ -0,0 +1,132 @@
+package commitlog
+
+import "testing"
+
+func TestRecordBatchEncodeDecodeRoundTrip(t *testing.T) {
+	b := NewRecordBatch(100)
+	b.AppendRecord(1000, []byte("k1"), []byte("v1"))
+	b.AppendRecord(1010, []byte("k2"), []byte("v2"))
+	b.AppendRecord(1015, nil, []byte("v3"))
+	b.AppendRecord(1040, []byte("k4"), nil) // tombstone
+
+	encoded, err := b.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	got, err := DecodeRecordBatch(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRecordBatch() error = %v", err)
+	}
+
+	if got.BaseOffset != 100 || got.BaseTimestamp != 1000 || got.MaxTimestamp != 1040 {
+		t.Errorf("decoded header = %+v, want BaseOffset=100 BaseTimestamp=1000 MaxTimestamp=1040", got)
+	}
+	if got.RecordCount() != 4 {
+		t.Fatalf("RecordCount() = %d, want 4", got.RecordCount())
+	}
+
+	wantOffsets := []int64{100, 101, 102, 103}
+	wantTimestamps := []int64{1000, 1010, 1015, 1040}
+	wantKeys := [][]byte{[]byte("k1"), []byte("k2"), nil, []byte("k4")}
+	wantValues := [][]byte{[]byte("v1"), []byte("v2"), []byte("v3"), nil}
+
+	i := 0
+	err = got.Iterate(func(offset, ts int64, key, value []byte) error {
+		if offset != wantOffsets[i] {
+			t.Errorf("record %d offset = %d, want %d", i, offset, wantOffsets[i])
+		}
+		if ts != wantTimestamps[i] {
+			t.Errorf("record %d timestamp = %d, want %d", i, ts, wantTimestamps[i])
+		}
+		if string(key) != string(wantKeys[i]) {
+			t.Errorf("record %d key = %q, want %q", i, key, wantKeys[i])
+		}
+		if string(value) != string(wantValues[i]) {
+			t.Errorf("record %d value = %q, want %q", i, value, wantValues[i])
+		}
+		i++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate() error = %v", err)
+	}
+}
+
+func TestRecordBatchDecodeRejectsCorruptCrc(t *testing.T) {
+	b := NewRecordBatch(0)
+	b.AppendRecord(1, []byte("k"), []byte("v"))
+	encoded, err := b.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	encoded[len(encoded)-1] ^= 0xFF // corrupt the last byte of the record section
+
+	if _, err := DecodeRecordBatch(encoded); err == nil {
+		t.Error("DecodeRecordBatch() on corrupted batch error = nil, want a CRC mismatch")
+	}
+}
+
+func TestSegmentWriteAndScanRoundTripMessage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSegment(dir, 0, 1<<20)
+	if err != nil {
+		t.Fatalf("newSegment() error = %v", err)
+	}
+
+	in := createTestMessage(1, 0, 42, []byte("k"), []byte("v"))
+	offset, err := s.Write(in)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if offset != 0 {
+		t.Errorf("Write() offset = %d, want 0", offset)
+	}
+
+	var got Message
+	if err := s.scan(func(_ int64, msg Message) error {
+		got = msg
+		return nil
+	}); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+
+	if got.Timestamp() != 42 || string(got.Key()) != "k" || string(got.Value()) != "v" {
+		t.Errorf("scan() round-tripped message = %+v, want timestamp=42 key=k value=v", got)
+	}
+}
+
+func TestSegmentWriteBatchAdvancesOffsetsByRecordCount(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSegment(dir, 0, 1<<20)
+	if err != nil {
+		t.Fatalf("newSegment() error = %v", err)
+	}
+
+	batch := NewRecordBatch(0)
+	batch.AppendRecord(1, []byte("k1"), []byte("v1"))
+	batch.AppendRecord(2, []byte("k2"), []byte("v2"))
+
+	base, err := s.WriteBatch(batch)
+	if err != nil {
+		t.Fatalf("WriteBatch() error = %v", err)
+	}
+	if base != 0 {
+		t.Errorf("WriteBatch() base offset = %d, want 0", base)
+	}
+	if s.NextOffset != 2 {
+		t.Errorf("NextOffset after WriteBatch() = %d, want 2", s.NextOffset)
+	}
+
+	var values []string
+	if err := s.scan(func(_ int64, msg Message) error {
+		values = append(values, string(msg.Value()))
+		return nil
+	}); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	if len(values) != 2 || values[0] != "v1" || values[1] != "v2" {
+		t.Errorf("scan() after WriteBatch() = %v, want [v1 v2]", values)
+	}
+}
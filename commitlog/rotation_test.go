@@ -0,0 +1,118 @@
+package commitlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogAppendRollsSegmentPastMaxSegmentAge(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, MaxSegmentAge: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	first := l.active()
+	stale := time.Now().Add(-time.Hour).UnixMilli()
+	if _, err := l.Append(createTestMessage(1, 0, stale, []byte("k"), []byte("v"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	if l.active() == first {
+		t.Error("Append() did not roll a segment whose oldest message is past MaxSegmentAge")
+	}
+	if len(l.segments) != 2 {
+		t.Errorf("len(l.segments) = %d, want 2", len(l.segments))
+	}
+}
+
+func TestLogAppendDoesNotRollWithinMaxSegmentAge(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, MaxSegmentAge: time.Hour})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	now := time.Now().UnixMilli()
+	first := l.active()
+	for i := 0; i < 3; i++ {
+		if _, err := l.Append(createTestMessage(1, 0, now, []byte("k"), []byte("v"))); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	if l.active() != first {
+		t.Error("Append() rolled a segment younger than MaxSegmentAge")
+	}
+}
+
+func TestLogAppendSyncsOnAppendWhenConfigured(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, SyncOnAppend: true})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(createTestMessage(1, 0, 1, []byte("k"), []byte("v"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	if l.appendsSinceSync != 0 {
+		t.Errorf("appendsSinceSync = %d, want 0 (SyncOnAppend syncs on every append)", l.appendsSinceSync)
+	}
+}
+
+func TestLogAppendSyncsEveryFlushMessages(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, FlushMessages: 2})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := l.Append(createTestMessage(1, 0, 1, []byte("k"), []byte("v"))); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	if l.appendsSinceSync != 1 {
+		t.Errorf("appendsSinceSync = %d, want 1 (5 appends, flushed every 2)", l.appendsSinceSync)
+	}
+}
+
+func TestLogSyncFlushesActiveSegment(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(createTestMessage(1, 0, 1, []byte("k"), []byte("v"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+}
+
+func TestLogFlushIntervalRunsBackgroundSync(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20, FlushInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(createTestMessage(1, 0, 1, []byte("k"), []byte("v"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	// The flusher runs Sync in the background; just give it a chance to
+	// tick at least once without racing on any exported state.
+	time.Sleep(10 * time.Millisecond)
+}
@@ -1,8 +1,13 @@
 package commitlog
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
 )
 
@@ -21,9 +26,322 @@ type segment struct {
 	BaseOffset int64
 	NextOffset int64
 	Position   int64
-	maxBytes   int64
-	path       string
-	suffix     string
+	// MaxTimestamp is the highest message timestamp written to this
+	// segment, used to evaluate RetentionMs during cleaning.
+	MaxTimestamp int64
+	// FirstTimestamp is the timestamp of the first message written to
+	// this segment, used to evaluate MaxSegmentAge. Zero until the first
+	// write lands.
+	FirstTimestamp int64
+	maxBytes       int64
+	path           string
+	suffix         string
 
 	sync.Mutex
 }
+
+// newSegment creates (or reopens) the segment rooted at baseOffset within
+// dir, along with its backing .log and .index files.
+func newSegment(dir string, baseOffset, maxBytes int64) (*segment, error) {
+	path := filepath.Join(dir, fmt.Sprintf(fileFormat, baseOffset, ""))
+
+	log, err := os.OpenFile(path+logSuffix, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := newIndex(path + indexSuffix)
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	s := &segment{
+		writer:     log,
+		reader:     log,
+		log:        log,
+		Index:      idx,
+		BaseOffset: baseOffset,
+		NextOffset: baseOffset,
+		maxBytes:   maxBytes,
+		path:       path,
+		suffix:     logSuffix,
+	}
+	err = s.scan(func(offset int64, _ Message) error {
+		s.NextOffset = offset + 1
+		return nil
+	})
+	if err == nil {
+		err = s.rebuildIndex()
+	}
+	if err != nil {
+		log.Close()
+		idx.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// batchFromMessage wraps a single Message as a one-record RecordBatch,
+// the shape segment.Write persists it in. Message itself remains the
+// compatibility surface for magic-byte 0/1 reads; RecordBatch is what
+// actually hits disk.
+func batchFromMessage(offset int64, msg Message) *RecordBatch {
+	var ts int64
+	if msg.MagicByte() > 0 {
+		ts = msg.Timestamp()
+	}
+	batch := NewRecordBatch(offset)
+	batch.AppendRecord(ts, msg.Key(), msg.Value())
+	return batch
+}
+
+// writeMessageAsBatch frames msg as a single-record RecordBatch and
+// writes it to w. It is shared by segment.Write and the compactor, which
+// writes survivors to a fresh file before renaming it over the original.
+func writeMessageAsBatch(w io.Writer, offset int64, msg Message) error {
+	encoded, err := batchFromMessage(offset, msg).Encode()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(encoded)
+	return err
+}
+
+// Write appends msg to the segment, persisted as a single-record
+// RecordBatch, and returns the offset it was written at. Callers are
+// responsible for rolling to a new segment once maxBytes is exceeded.
+func (s *segment) Write(msg Message) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	offset := s.NextOffset
+	batch := batchFromMessage(offset, msg)
+	encoded, err := batch.Encode()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Index.WriteEntry(Entry{Offset: offset, Position: s.Position}); err != nil {
+		return 0, err
+	}
+	if _, err := s.writer.Write(encoded); err != nil {
+		return 0, err
+	}
+
+	if offset == s.BaseOffset {
+		s.FirstTimestamp = batch.BaseTimestamp
+	}
+	s.Position += int64(len(encoded))
+	s.NextOffset++
+	if batch.MaxTimestamp > s.MaxTimestamp {
+		s.MaxTimestamp = batch.MaxTimestamp
+	}
+	return offset, nil
+}
+
+// WriteBatch appends an already-built RecordBatch as a single physical
+// unit, advancing NextOffset by its record count. Unlike Write, this
+// indexes only the batch's base offset, not every record in it, so
+// looking up an interior offset costs a short forward scan from there.
+func (s *segment) WriteBatch(batch *RecordBatch) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	baseOffset := s.NextOffset
+	batch.BaseOffset = baseOffset
+	for i := range batch.records {
+		batch.records[i].offset = baseOffset + int64(i)
+	}
+
+	encoded, err := batch.Encode()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Index.WriteEntry(Entry{Offset: baseOffset, Position: s.Position}); err != nil {
+		return 0, err
+	}
+	if _, err := s.writer.Write(encoded); err != nil {
+		return 0, err
+	}
+
+	if baseOffset == s.BaseOffset {
+		s.FirstTimestamp = batch.BaseTimestamp
+	}
+	s.Position += int64(len(encoded))
+	s.NextOffset += int64(batch.RecordCount())
+	if batch.MaxTimestamp > s.MaxTimestamp {
+		s.MaxTimestamp = batch.MaxTimestamp
+	}
+	return baseOffset, nil
+}
+
+// scan walks every record in the segment in offset order, invoking fn
+// with each record's offset and its Message compatibility view. It reads
+// the on-disk file directly rather than through s.reader, so it must not
+// run concurrently with a rename/truncate of the segment (e.g.
+// compaction).
+func (s *segment) scan(fn func(offset int64, msg Message) error) error {
+	f, err := os.Open(s.path + s.suffix)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := newScanner(f)
+	for {
+		offset, msg, err := sc.Scan()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(offset, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// rebuildIndex discards the segment's index and regenerates it from the
+// current contents of its log file, recomputing Position along the way.
+// It walks physical batch frames rather than decoding every record, so
+// it indexes WriteBatch's multi-record frames the same sparse way they
+// were originally written.
+func (s *segment) rebuildIndex() error {
+	if err := s.Index.Truncate(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(s.path + s.suffix)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var pos int64
+	for {
+		header := make([]byte, recordBatchHeaderSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		batchLength := Encoding.Uint32(header[8:12])
+		bodyLength := int64(batchLength) - recordBatchTailSize
+		if _, err := io.CopyN(io.Discard, r, bodyLength); err != nil {
+			return err
+		}
+
+		baseOffset := int64(Encoding.Uint64(header[0:8]))
+		if err := s.Index.WriteEntry(Entry{Offset: baseOffset, Position: pos}); err != nil {
+			return err
+		}
+		pos += int64(recordBatchHeaderSize) + bodyLength
+	}
+	s.Position = pos
+	return nil
+}
+
+// Remove closes and deletes the segment's log and index files.
+func (s *segment) Remove() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.log.Close(); err != nil {
+		return err
+	}
+	if err := s.Index.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path + s.suffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.Index.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Scanner sequentially decodes (offset, Message) entries from a segment's
+// on-disk log, which is physically a sequence of RecordBatch frames. A
+// batch with more than one record is unpacked and yielded one record at
+// a time; each record is reconstituted as a v1 Message for callers that
+// only care about the compatibility view.
+type Scanner struct {
+	r     *bufio.Reader
+	batch *RecordBatch
+	idx   int
+}
+
+func newScanner(r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r)}
+}
+
+// Scan reads the next record, returning io.EOF once the underlying
+// reader is exhausted cleanly on a batch boundary.
+func (sc *Scanner) Scan() (offset int64, msg Message, err error) {
+	if sc.batch == nil || sc.idx >= sc.batch.RecordCount() {
+		if err := sc.nextBatch(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	r := sc.batch.records[sc.idx]
+	sc.idx++
+	return r.offset, messageFromRecord(r.timestamp, r.key, r.value), nil
+}
+
+func (sc *Scanner) nextBatch() error {
+	header := make([]byte, recordBatchHeaderSize)
+	if _, err := io.ReadFull(sc.r, header); err != nil {
+		return err
+	}
+	batchLength := Encoding.Uint32(header[8:12])
+	rest := make([]byte, int64(batchLength)-recordBatchTailSize)
+	if _, err := io.ReadFull(sc.r, rest); err != nil {
+		return err
+	}
+
+	batch, err := DecodeRecordBatch(append(header, rest...))
+	if err != nil {
+		return err
+	}
+	sc.batch = batch
+	sc.idx = 0
+	return nil
+}
+
+// messageFromRecord rebuilds a v1 Message from a decoded RecordBatch
+// record, the Message-API compatibility shim the rest of the package
+// reads through.
+func messageFromRecord(ts int64, key, value []byte) Message {
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, 4)) // crc, filled in below
+	buf.WriteByte(1)           // magic byte: v1, carries a timestamp
+	buf.WriteByte(0)           // attributes
+
+	tsBuf := make([]byte, 8)
+	Encoding.PutUint64(tsBuf, uint64(ts))
+	buf.Write(tsBuf)
+
+	writeLengthPrefixed(buf, key)
+	writeLengthPrefixed(buf, value)
+
+	msg := buf.Bytes()
+	Encoding.PutUint32(msg[0:4], crc32.ChecksumIEEE(msg[4:]))
+	return Message(msg)
+}
+
+func writeLengthPrefixed(buf *bytes.Buffer, data []byte) {
+	lenBuf := make([]byte, 4)
+	if data == nil {
+		Encoding.PutUint32(lenBuf, 0xFFFFFFFF)
+	} else {
+		Encoding.PutUint32(lenBuf, uint32(len(data)))
+	}
+	buf.Write(lenBuf)
+	if data != nil {
+		buf.Write(data)
+	}
+}
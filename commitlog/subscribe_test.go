@@ -0,0 +1,159 @@
+package commitlog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogSubscribeReplaysHistoryThenFollowsNewAppends(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(createTestMessage(1, 0, 1, []byte("k1"), []byte("v1"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	msgs, err := l.Subscribe(ctx, 0)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	first := recvMessage(t, msgs)
+	if string(first.Value()) != "v1" {
+		t.Errorf("first message = %q, want %q", first.Value(), "v1")
+	}
+
+	if _, err := l.Append(createTestMessage(1, 0, 2, []byte("k2"), []byte("v2"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	second := recvMessage(t, msgs)
+	if string(second.Value()) != "v2" {
+		t.Errorf("second message = %q, want %q", second.Value(), "v2")
+	}
+}
+
+func TestLogSubscribeFollowsAcrossSegmentRoll(t *testing.T) {
+	dir := t.TempDir()
+	// A tiny MaxSegmentBytes forces a roll after the first append.
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	msgs, err := l.Subscribe(ctx, 0)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	if _, err := l.Append(createTestMessage(1, 0, 1, []byte("k1"), []byte("v1"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if len(l.Segments()) != 2 {
+		t.Fatalf("Segments() = %d, want 2 after a roll", len(l.Segments()))
+	}
+	if _, err := l.Append(createTestMessage(1, 0, 2, []byte("k2"), []byte("v2"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	first := recvMessage(t, msgs)
+	if string(first.Value()) != "v1" {
+		t.Errorf("first message = %q, want %q", first.Value(), "v1")
+	}
+	second := recvMessage(t, msgs)
+	if string(second.Value()) != "v2" {
+		t.Errorf("second message (across roll) = %q, want %q", second.Value(), "v2")
+	}
+}
+
+func TestLogSubscribeHonorsContextCancellation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs, err := l.Subscribe(ctx, 0)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Errorf("expected channel to close on cancellation, got a message instead")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel did not close within 2s of context cancellation")
+	}
+}
+
+func TestLogSubscribeBlocksOnOffsetBeyondHighWaterMark(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLog(Options{Path: dir, MaxSegmentBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// startOffset is ahead of the log's (empty) high-water mark, which a
+	// stale cached hwm or a future fetch offset can produce. Follow must
+	// block on l.cond rather than spin waiting for offset == NextOffset.
+	msgs, err := l.Subscribe(ctx, 100)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Fatal("expected no message before any append past offset 100")
+		}
+		t.Fatal("channel closed unexpectedly")
+	case <-time.After(200 * time.Millisecond):
+		// Expected: Follow is parked in cond.Wait(), not spinning.
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Errorf("expected channel to close on cancellation, got a message instead")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel did not close within 2s of context cancellation")
+	}
+}
+
+func recvMessage(t *testing.T, msgs <-chan Message) Message {
+	t.Helper()
+	select {
+	case msg, ok := <-msgs:
+		if !ok {
+			t.Fatal("channel closed unexpectedly")
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}